@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dlqEntry is what actually gets stored in dlqKey: the envelope at the point
+// it was given up on, plus when that happened, so /dlq/list can show why and
+// when a job landed here.
+type dlqEntry struct {
+	jobEnvelope
+	DeadLetteredAt int64 `json:"dead_lettered_at"`
+}
+
+// deadLetter moves a job that has exhausted its retries (or can never
+// succeed) onto the dead-letter queue for manual inspection via /dlq/list
+// and /dlq/replay.
+func deadLetter(ctx context.Context, rdb redis.UniversalClient, envelope jobEnvelope) {
+	entry := dlqEntry{jobEnvelope: envelope, DeadLetteredAt: time.Now().Unix()}
+
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("DLQ: ERROR - failed to marshal dead-letter entry: %v", err)
+		return
+	}
+
+	if err := rdb.RPush(ctx, dlqKey, entryJson).Err(); err != nil {
+		log.Printf("DLQ: ERROR - failed to push dead-letter entry: %v", err)
+		return
+	}
+
+	log.Printf("DLQ: job dead-lettered after %d attempts: %s", envelope.Attempts, envelope.LastError)
+}
+
+// dnDLQEntry is what gets stored in dnDLQKey when a partner DN ping
+// exhausts its retries. It's tracked separately from dlqKey because a DN
+// ping failure is independent of the job's own success: the transaction log
+// insert may already have succeeded by the time the ping gives up.
+type dnDLQEntry struct {
+	TargetURL      string `json:"target_url"`
+	LastError      string `json:"last_error"`
+	DeadLetteredAt int64  `json:"dead_lettered_at"`
+}
+
+// deadLetterDN moves a partner DN ping that has exhausted its retries onto
+// its own dead-letter queue for manual inspection via /dlq/dn/list and
+// /dlq/dn/replay.
+func deadLetterDN(ctx context.Context, rdb redis.UniversalClient, targetURL string, lastErr error) {
+	errMsg := ""
+	if lastErr != nil {
+		errMsg = lastErr.Error()
+	}
+	entry := dnDLQEntry{TargetURL: targetURL, LastError: errMsg, DeadLetteredAt: time.Now().Unix()}
+
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("DN DLQ: ERROR - failed to marshal dead-letter entry: %v", err)
+		return
+	}
+
+	if err := rdb.RPush(ctx, dnDLQKey, entryJson).Err(); err != nil {
+		log.Printf("DN DLQ: ERROR - failed to push dead-letter entry: %v", err)
+		return
+	}
+
+	log.Printf("DN DLQ: partner ping dead-lettered: %s", errMsg)
+}
+
+// startDLQServer exposes a small HTTP API for operators to inspect and
+// replay dead-lettered jobs: GET /dlq/list returns the current backlog, and
+// POST /dlq/replay drains it back onto the main queue with a reset attempt
+// counter so it gets a fresh run of retries.
+func startDLQServer(addr string, rdb redis.UniversalClient) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dlq/list", func(w http.ResponseWriter, r *http.Request) {
+		handleDLQList(w, r, rdb)
+	})
+	mux.HandleFunc("/dlq/replay", func(w http.ResponseWriter, r *http.Request) {
+		handleDLQReplay(w, r, rdb)
+	})
+	mux.HandleFunc("/dlq/dn/list", func(w http.ResponseWriter, r *http.Request) {
+		handleDNDLQList(w, r, rdb)
+	})
+	mux.HandleFunc("/dlq/dn/replay", func(w http.ResponseWriter, r *http.Request) {
+		handleDNDLQReplay(w, r, rdb)
+	})
+
+	log.Printf("DLQ HTTP API listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("DLQ HTTP API stopped: %v", err)
+	}
+}
+
+func handleDLQList(w http.ResponseWriter, r *http.Request, rdb redis.UniversalClient) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := rdb.LRange(r.Context(), dlqKey, 0, -1).Result()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("["))
+	for i, entry := range entries {
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		w.Write([]byte(entry))
+	}
+	w.Write([]byte("]"))
+}
+
+// handleDLQReplay drains every entry currently on the DLQ and re-enqueues it
+// onto the main queue with attempts reset, so it's retried from scratch
+// instead of immediately being dead-lettered again on the first failure.
+func handleDLQReplay(w http.ResponseWriter, r *http.Request, rdb redis.UniversalClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	replayed := 0
+
+	for {
+		raw, err := rdb.LPop(ctx, dlqKey).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var entry dlqEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			log.Printf("DLQ: WARNING - skipping unparsable entry during replay: %v", err)
+			continue
+		}
+
+		retryEnvelope := newJobEnvelope(entry.Payload)
+		retryJson, err := json.Marshal(retryEnvelope)
+		if err != nil {
+			log.Printf("DLQ: WARNING - failed to marshal replay envelope: %v", err)
+			continue
+		}
+
+		if err := rdb.LPush(ctx, queueKey, retryJson).Err(); err != nil {
+			log.Printf("DLQ: WARNING - failed to re-queue replayed job: %v", err)
+			continue
+		}
+		replayed++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"replayed": replayed})
+}
+
+// handleDNDLQList returns the current backlog of partner DN pings that
+// exhausted their retries.
+func handleDNDLQList(w http.ResponseWriter, r *http.Request, rdb redis.UniversalClient) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := rdb.LRange(r.Context(), dnDLQKey, 0, -1).Result()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("["))
+	for i, entry := range entries {
+		if i > 0 {
+			w.Write([]byte(","))
+		}
+		w.Write([]byte(entry))
+	}
+	w.Write([]byte("]"))
+}
+
+// handleDNDLQReplay drains every entry currently on the DN dead-letter queue
+// and retries the ping inline (with the same backoff/maxAttempts policy as
+// the original attempt), dead-lettering it again if it still can't succeed.
+func handleDNDLQReplay(w http.ResponseWriter, r *http.Request, rdb redis.UniversalClient) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	replayed := 0
+
+	for {
+		raw, err := rdb.LPop(ctx, dnDLQKey).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var entry dnDLQEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			log.Printf("DN DLQ: WARNING - skipping unparsable entry during replay: %v", err)
+			continue
+		}
+
+		sendDNPingWithRetry(ctx, -1, entry.TargetURL, rdb)
+		replayed++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"replayed": replayed})
+}
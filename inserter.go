@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultInsertBatchSize and defaultInsertFlushInterval are the batching
+// knobs for LogInserter, overridable via BN_INSERT_BATCH_SIZE and
+// BN_INSERT_FLUSH_MS.
+const (
+	defaultInsertBatchSize     = 500
+	defaultInsertFlushInterval = 100 * time.Millisecond
+)
+
+// insertRequest carries one row through the inserter along with a channel to
+// deliver the outcome of whichever batch (or per-row fallback) it ends up in.
+type insertRequest struct {
+	entry    tmvh_transaction_logs
+	resultCh chan error
+}
+
+// LogInserter batches tmvh_transaction_logs rows from many concurrent
+// threadWorker calls into a single multi-row INSERT, so throughput isn't
+// capped at one DB round trip per job. A batch is flushed once it reaches
+// batchSize rows or flushEvery has elapsed, whichever comes first.
+type LogInserter struct {
+	ctx        context.Context
+	db         *gorm.DB
+	batchSize  int
+	flushEvery time.Duration
+	requests   chan insertRequest
+}
+
+// NewLogInserter starts the background flush loop and returns the inserter.
+// ctx is the root application context; it governs the batch INSERT calls
+// themselves (as opposed to the per-request ctx passed to Insert, which only
+// governs how long that caller is willing to wait for a result).
+func NewLogInserter(ctx context.Context, db *gorm.DB, batchSize int, flushEvery time.Duration) *LogInserter {
+	ins := &LogInserter{
+		ctx:        ctx,
+		db:         db,
+		batchSize:  batchSize,
+		flushEvery: flushEvery,
+		requests:   make(chan insertRequest, batchSize*2),
+	}
+	go ins.run()
+	return ins
+}
+
+// Insert enqueues a row and blocks until the batch it lands in (or its
+// per-row fallback) has been committed or failed, so the caller can make the
+// Redis ack/retry decision off the real outcome for that specific row.
+func (ins *LogInserter) Insert(ctx context.Context, entry tmvh_transaction_logs) error {
+	req := insertRequest{entry: entry, resultCh: make(chan error, 1)}
+	ins.requests <- req
+
+	select {
+	case err := <-req.resultCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (ins *LogInserter) run() {
+	ticker := time.NewTicker(ins.flushEvery)
+	defer ticker.Stop()
+
+	batch := make([]insertRequest, 0, ins.batchSize)
+	for {
+		select {
+		case req := <-ins.requests:
+			batch = append(batch, req)
+			if len(batch) >= ins.batchSize {
+				ins.flush(batch)
+				batch = make([]insertRequest, 0, ins.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				ins.flush(batch)
+				batch = make([]insertRequest, 0, ins.batchSize)
+			}
+		}
+	}
+}
+
+// flush commits a batch in a single INSERT. If the batch insert fails, it
+// falls back to inserting each row individually so one bad row (a
+// constraint violation, say) doesn't block the rest of the batch from being
+// acknowledged.
+func (ins *LogInserter) flush(batch []insertRequest) {
+	rows := make([]tmvh_transaction_logs, len(batch))
+	for i, req := range batch {
+		rows[i] = req.entry
+	}
+
+	db := ins.db.WithContext(ins.ctx)
+
+	err := db.CreateInBatches(&rows, len(rows)).Error
+	if err == nil {
+		for _, req := range batch {
+			req.resultCh <- nil
+		}
+		return
+	}
+
+	log.Printf("Inserter: WARNING - batch insert of %d rows failed, falling back to per-row inserts: %v", len(rows), err)
+	for i, req := range batch {
+		req.resultCh <- db.Create(&rows[i]).Error
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newInserterTestDB opens a fresh in-memory sqlite DB migrated for
+// tmvh_transaction_logs, isolated per test via a unique DSN.
+func newInserterTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open in-memory sqlite db: %v", err)
+	}
+	if err := db.AutoMigrate(&tmvh_transaction_logs{}); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	return db
+}
+
+// TestLogInserterFlush_BatchSucceeds covers the happy path: every row in the
+// batch commits together and every resultCh gets a nil error.
+func TestLogInserterFlush_BatchSucceeds(t *testing.T) {
+	ins := &LogInserter{ctx: context.Background(), db: newInserterTestDB(t)}
+
+	batch := []insertRequest{
+		{entry: tmvh_transaction_logs{ID: "a"}, resultCh: make(chan error, 1)},
+		{entry: tmvh_transaction_logs{ID: "b"}, resultCh: make(chan error, 1)},
+	}
+	ins.flush(batch)
+
+	for _, req := range batch {
+		if err := <-req.resultCh; err != nil {
+			t.Errorf("row %s: expected nil error, got %v", req.entry.ID, err)
+		}
+	}
+
+	var count int64
+	ins.db.Model(&tmvh_transaction_logs{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 rows inserted, got %d", count)
+	}
+}
+
+// TestLogInserterFlush_FallsBackPerRowOnConflict covers the fallback path: a
+// batch insert that fails because one row collides on the primary key still
+// commits every other row individually instead of discarding the whole batch.
+func TestLogInserterFlush_FallsBackPerRowOnConflict(t *testing.T) {
+	db := newInserterTestDB(t)
+	if err := db.Create(&tmvh_transaction_logs{ID: "dup"}).Error; err != nil {
+		t.Fatalf("failed to seed duplicate row: %v", err)
+	}
+	ins := &LogInserter{ctx: context.Background(), db: db}
+
+	batch := []insertRequest{
+		{entry: tmvh_transaction_logs{ID: "dup"}, resultCh: make(chan error, 1)},
+		{entry: tmvh_transaction_logs{ID: "fresh"}, resultCh: make(chan error, 1)},
+	}
+	ins.flush(batch)
+
+	if err := <-batch[0].resultCh; err == nil {
+		t.Error("expected the duplicate-ID row to fail, got nil error")
+	}
+	if err := <-batch[1].resultCh; err != nil {
+		t.Errorf("expected the fresh row to still succeed, got %v", err)
+	}
+
+	var count int64
+	db.Model(&tmvh_transaction_logs{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 rows present (1 seed + 1 fresh), got %d", count)
+	}
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,6 +24,56 @@ import (
 	"gorm.io/gorm"
 )
 
+// Queue key layout for the reliable BRPOPLPUSH-based job queue. Producers
+// LPUSH job payloads onto queueKey; each worker atomically moves a job into
+// its own in-flight list so a crash mid-processing doesn't lose it.
+const (
+	queueKey            = "tmvh-transaction-callback-api:queue"
+	processingKeyPrefix = "tmvh-transaction-callback-api:processing:"
+	legacyKeyPattern    = "tmvh-transaction-callback-api:*"
+	legacyKeyspaceChan  = "__keyspace@0__:" + legacyKeyPattern
+	dlqKey              = "tmvh-transaction-callback-dlq"
+	dnDLQKey            = "tmvh-transaction-callback-dn-dlq"
+
+	staleProcessingAfter = 5 * time.Minute
+	recoveryInterval     = 30 * time.Second
+
+	maxAttempts  = 10
+	baseBackoff  = 2 * time.Second
+	capBackoff   = 5 * time.Minute
+	notReadyWait = 500 * time.Millisecond
+
+	defaultDrainTimeout = 30 * time.Second
+)
+
+// jobEnvelope wraps a job's payload with the retry bookkeeping needed for
+// exponential backoff and dead-lettering. It's what actually gets LPUSHed
+// onto queueKey (and, wrapped in a dlqEntry, RPUSHed onto dlqKey);
+// TransactionData only ever lives inside Payload.
+type jobEnvelope struct {
+	Payload       string `json:"payload"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	LastError     string `json:"last_error"`
+}
+
+// newJobEnvelope wraps a freshly produced payload with a zeroed attempt counter.
+func newJobEnvelope(payload string) jobEnvelope {
+	return jobEnvelope{Payload: payload}
+}
+
+// nextBackoff computes the exponential delay (capped, with jitter) before
+// attempt number `attempts` should be retried. The jitter adds up to half of
+// the capped delay, so the result falls in [delay, 1.5*delay).
+func nextBackoff(attempts int) time.Duration {
+	delay := baseBackoff * time.Duration(1<<uint(attempts))
+	if delay > capBackoff {
+		delay = capBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay) / 2))
+	return delay + jitter
+}
+
 // main sets up the database and Redis connections and starts the background worker.
 func main() {
 	// It's better to load these from environment variables for security and flexibility.
@@ -28,13 +84,24 @@ func main() {
 		log.Fatal("BN_REDIS_URL and BN_DB_URL environment variables must be set.")
 	}
 
-	// Configure Redis client with a connection pool.
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     redisConnection,
-		Password: "",  // No password by default
-		DB:       0,   // Default DB
-		PoolSize: 100, // Connection pools
-	})
+	// Cancelled on SIGTERM/SIGINT. Only governs whether workers pull *new* work
+	// off the queue (BRPopLPush, the recovery scan, the legacy bridge); it must
+	// never be threaded into a job already in flight, or the job's GORM query,
+	// HTTP ping, and batched insert would all abort the instant the signal
+	// arrives instead of getting to finish within BN_DRAIN_TIMEOUT.
+	rootCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Governs in-flight job work (GORM calls, the DN HTTP ping, batched
+	// inserts) independently of rootCtx, so a SIGTERM doesn't cancel work
+	// that's already running. Cancelled explicitly once the drain below
+	// finishes or times out.
+	workCtx, cancelWork := context.WithCancel(context.Background())
+	defer cancelWork()
+
+	// Build the Redis client. newRedisClient inspects the BN_REDIS_URL scheme to
+	// decide between a standalone, Sentinel-backed, or Cluster connection.
+	rdb := newRedisClient(redisConnection)
 
 	// Configure database client with a connection pool.
 	db, errDatabase := gorm.Open(postgres.Open(dbConnection), &gorm.Config{})
@@ -51,112 +118,294 @@ func main() {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetConnMaxLifetime(5 * time.Minute)
 
+	// Batches concurrent threadWorker inserts into a single multi-row INSERT.
+	batchSize := getEnvInt("BN_INSERT_BATCH_SIZE", defaultInsertBatchSize)
+	flushEvery := time.Duration(getEnvInt("BN_INSERT_FLUSH_MS", int(defaultInsertFlushInterval/time.Millisecond))) * time.Millisecond
+	inserter := NewLogInserter(workCtx, db, batchSize, flushEvery)
+
+	// Tracks jobs currently being processed so shutdown can wait for them to drain.
+	var inFlight sync.WaitGroup
+
 	// Start the background worker in a separate goroutine.
-	go backgroundWorker(rdb, db)
+	go backgroundWorker(rootCtx, workCtx, rdb, db, inserter, &inFlight)
+
+	// Expose the DLQ inspection/replay API. Defaults to :8090, override with BN_DLQ_ADDR.
+	dlqAddr := os.Getenv("BN_DLQ_ADDR")
+	if dlqAddr == "" {
+		dlqAddr = ":8090"
+	}
+	go startDLQServer(dlqAddr, rdb)
+
+	// Expose Prometheus metrics and pprof profiling. Defaults to :9090, override with BN_METRICS_ADDR.
+	metricsAddr := os.Getenv("BN_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = ":9090"
+	}
+	go startMetricsServer(metricsAddr)
+	go reportPoolStats(rdb, db)
 
 	log.Println("Application started successfully. Background worker is running.")
-	// Keep the main function running indefinitely so the background goroutine can live.
-	select {}
-}
+	// Block until SIGTERM/SIGINT, then drain in-flight jobs before tearing down the pools.
+	<-rootCtx.Done()
+	log.Println("Shutdown signal received. Draining in-flight jobs...")
+
+	drained := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(drained)
+	}()
+
+	drainTimeout := getEnvDuration("BN_DRAIN_TIMEOUT", defaultDrainTimeout)
+	select {
+	case <-drained:
+		log.Println("All in-flight jobs finished.")
+	case <-time.After(drainTimeout):
+		log.Printf("Drain timeout of %s exceeded; shutting down with jobs still in flight.", drainTimeout)
+	}
+	// Only now cut off workCtx: any job still running past the drain window
+	// gets its in-flight DB/HTTP calls cancelled here, not at the SIGTERM itself.
+	cancelWork()
 
-// backgroundWorker continuously scans Redis for jobs and dispatches them to worker goroutines.
-func backgroundWorker(rdb *redis.Client, db *gorm.DB) {
-	var ctx = context.Background()
-	const WAIT_INTERVAL = 17 * time.Second // Reduced wait time for more responsive scanning
-	var cursor uint64 = 0
-	const matchPattern = "tmvh-transaction-callback-api:*"
-	const count = int64(100)
+	if err := rdb.Close(); err != nil {
+		log.Printf("WARNING - error closing Redis client: %v", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.Printf("WARNING - error closing database pool: %v", err)
+	}
+	log.Println("Shutdown complete.")
+}
 
+// backgroundWorker starts the reliable-queue consumers, the stale in-flight
+// job recovery routine, and the legacy keyspace-notification migration
+// bridge (see bridgeLegacyKeyspaceNotifications). It returns once pollCtx is
+// cancelled; callers that need to wait for in-flight jobs to finish should
+// wait on the inFlight WaitGroup separately. pollCtx only gates pulling new
+// work off the queue; workCtx governs the work itself and outlives pollCtx
+// so an in-flight job isn't cancelled out from under it by the shutdown signal.
+func backgroundWorker(pollCtx, workCtx context.Context, rdb redis.UniversalClient, db *gorm.DB, inserter *LogInserter, inFlight *sync.WaitGroup) {
 	log.Println("##### TMVH TRANSACTION WORKER RUNNING #####")
 
-	var wg sync.WaitGroup
+	workerConcurrency := getEnvInt("BN_WORKER_CONCURRENCY", runtime.NumCPU()*4)
+	for workerID := 0; workerID < workerConcurrency; workerID++ {
+		go consumeQueue(workerID, rdb, pollCtx, workCtx, db, inserter, inFlight)
+	}
+
+	go recoverStaleJobs(rdb, pollCtx)
+	go bridgeLegacyKeyspaceNotifications(rdb, pollCtx)
+
+	<-pollCtx.Done()
+}
+
+// consumeQueue blocks on BRPOPLPUSH, atomically moving the next job envelope
+// from the shared queue into this worker's own in-flight list, then owns its
+// full retry lifecycle: deferring jobs whose backoff hasn't elapsed,
+// re-queuing failed jobs with exponential backoff, and dead-lettering jobs
+// that can never succeed (malformed payload) or have exhausted their
+// attempts. The envelope is only removed from the in-flight list once its
+// fate (success, retry, or dead-letter) has been decided, so recoverStaleJobs
+// can re-queue it if this worker dies first.
+//
+// pollCtx gates the blocking BRPopLPush call only, so a SIGTERM stops this
+// worker from picking up new jobs; workCtx is used for everything done to a
+// job already popped (processing it, and recording its retry/dead-letter
+// outcome), so that work gets to finish instead of aborting mid-flight.
+func consumeQueue(workerID int, rdb redis.UniversalClient, pollCtx, workCtx context.Context, db *gorm.DB, inserter *LogInserter, inFlight *sync.WaitGroup) {
+	processingKey := fmt.Sprintf("%s%d", processingKeyPrefix, workerID)
 
 	for {
-		// Perform the Redis scan.
-		keys, newCursor, err := rdb.Scan(ctx, cursor, matchPattern, count).Result()
+		raw, err := rdb.BRPopLPush(pollCtx, queueKey, processingKey, 0).Result()
 		if err != nil {
-			// FIX: Instead of panicking, log the error and wait before retrying.
-			// This makes the worker resilient to temporary Redis connection issues.
-			log.Printf("ERROR scanning Redis: %v. Retrying in %s", err, WAIT_INTERVAL)
-			time.Sleep(WAIT_INTERVAL)
-			continue // Continue to the next loop iteration.
+			if pollCtx.Err() != nil {
+				// Shutting down: stop pulling new work and let main() drain what's already in flight.
+				return
+			}
+			if err != redis.Nil {
+				log.Printf("Consumer %d: ERROR - BRPopLPush failed: %v. Retrying shortly.", workerID, err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		var envelope jobEnvelope
+		if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+			log.Printf("Consumer %d: ERROR - malformed job envelope, dead-lettering: %v", workerID, err)
+			deadLetter(workCtx, rdb, jobEnvelope{Payload: raw, LastError: err.Error()})
+			rdb.LRem(workCtx, processingKey, 1, raw)
+			continue
+		}
+
+		if envelope.NextAttemptAt > time.Now().Unix() {
+			// Not due for retry yet; hand it back to the queue and let another pop pick it up later.
+			rdb.LPush(workCtx, queueKey, raw)
+			rdb.LRem(workCtx, processingKey, 1, raw)
+			time.Sleep(notReadyWait)
+			continue
+		}
+
+		inFlight.Add(1)
+		jobErr := threadWorker(workerID, envelope.Payload, rdb, workCtx, db, inserter)
+		// Called once the outcome below is recorded, so the drain in main() also
+		// waits for it.
+		finish := func() {
+			rdb.LRem(workCtx, processingKey, 1, raw)
+			inFlight.Done()
+		}
+
+		if jobErr == nil {
+			jobsProcessedTotal.WithLabelValues("success").Inc()
+			finish()
+			continue
+		}
+
+		if errors.Is(jobErr, errMalformedPayload) {
+			// Retrying a payload that can never parse just burns attempts; dead-letter it immediately.
+			jobsProcessedTotal.WithLabelValues("malformed").Inc()
+			deadLetter(workCtx, rdb, jobEnvelope{Payload: envelope.Payload, Attempts: envelope.Attempts, LastError: jobErr.Error()})
+			finish()
+			continue
+		}
+
+		envelope.Attempts++
+		envelope.LastError = jobErr.Error()
+		if envelope.Attempts >= maxAttempts {
+			jobsProcessedTotal.WithLabelValues("dlq").Inc()
+			deadLetter(workCtx, rdb, envelope)
+			finish()
+			continue
+		}
+		jobsProcessedTotal.WithLabelValues("retry").Inc()
+
+		envelope.NextAttemptAt = time.Now().Add(nextBackoff(envelope.Attempts)).Unix()
+		retryJson, marshalErr := json.Marshal(envelope)
+		if marshalErr != nil {
+			log.Printf("Consumer %d: ERROR - failed to marshal retry envelope: %v", workerID, marshalErr)
+			finish()
+			continue
+		}
+		if err := rdb.LPush(workCtx, queueKey, retryJson).Err(); err != nil {
+			log.Printf("Consumer %d: ERROR - failed to re-queue job for retry: %v", workerID, err)
+		}
+		finish()
+	}
+}
+
+// recoverStaleJobs periodically looks for in-flight jobs left behind by a
+// worker that crashed or was restarted mid-processing and re-pushes them
+// onto the main queue so another consumer can pick them up.
+func recoverStaleJobs(rdb redis.UniversalClient, ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(recoveryInterval):
 		}
 
-		if len(keys) > 0 {
-			log.Printf("Found %d keys to process in this batch.", len(keys))
-			for i, key := range keys {
-				valJson, err := rdb.Get(ctx, key).Result()
+		var cursor uint64
+		for {
+			processingKeys, newCursor, err := rdb.Scan(ctx, cursor, processingKeyPrefix+"*", 100).Result()
+			if err != nil {
+				log.Printf("Recovery: ERROR scanning processing lists: %v", err)
+				break
+			}
+
+			for _, processingKey := range processingKeys {
+				idleTime, err := rdb.ObjectIdleTime(ctx, processingKey).Result()
 				if err != nil {
-					// FIX: Instead of log.Fatal, log the error and skip this specific key.
-					// This allows the worker to continue with other keys in the batch.
-					log.Printf("ERROR getting value for key %s: %v. Skipping.", key, err)
+					// Notably returned by Redis when maxmemory-policy is LFU, since OBJECT
+					// IDLETIME requires an LRU policy -- log distinctly so that silently
+					// disabling crash recovery for every processing list is observable.
+					log.Printf("Recovery: ERROR - OBJECT IDLETIME failed for %s: %v", processingKey, err)
 					continue
 				}
+				if idleTime < staleProcessingAfter {
+					continue
+				}
+
+				// Drain the stale in-flight list back onto the main queue.
+				for {
+					_, err := rdb.RPopLPush(ctx, processingKey, queueKey).Result()
+					if err == redis.Nil {
+						break
+					}
+					if err != nil {
+						log.Printf("Recovery: ERROR re-queuing job from %s: %v", processingKey, err)
+						break
+					}
+					log.Printf("Recovery: re-queued stale job from %s", processingKey)
+				}
+			}
 
-				wg.Add(1)
-				// Pass the original Redis key to the worker for reliable deletion later.
-				go threadWorker(i, &wg, valJson, rdb, ctx, db, key)
+			cursor = newCursor
+			if cursor == 0 {
+				break
 			}
 		}
+	}
+}
+
+// bridgeLegacyKeyspaceNotifications subscribes to keyspace notifications for
+// the old "SET tmvh-transaction-callback-api:<id>" producer format and
+// enqueues the payload onto the reliable queue. This is a migration-period
+// shim and can be deleted once every producer LPUSHes onto queueKey directly.
+func bridgeLegacyKeyspaceNotifications(rdb redis.UniversalClient, ctx context.Context) {
+	pubsub := rdb.PSubscribe(ctx, legacyKeyspaceChan)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		if msg.Payload != "set" {
+			continue
+		}
+
+		// Channel is "__keyspace@0__:tmvh-transaction-callback-api:<id>"; recover the key name.
+		legacyKey := strings.TrimPrefix(msg.Channel, "__keyspace@0__:")
+
+		valJson, err := rdb.Get(ctx, legacyKey).Result()
+		if err != nil {
+			log.Printf("Legacy bridge: ERROR getting value for key %s: %v", legacyKey, err)
+			continue
+		}
+
+		envelopeJson, err := json.Marshal(newJobEnvelope(valJson))
+		if err != nil {
+			log.Printf("Legacy bridge: ERROR marshaling envelope for key %s: %v", legacyKey, err)
+			continue
+		}
 
-		cursor = newCursor
-		// If the cursor is 0, the scan of the entire keyspace is complete for now.
-		if cursor == 0 {
-			time.Sleep(WAIT_INTERVAL)
+		if err := rdb.LPush(ctx, queueKey, envelopeJson).Err(); err != nil {
+			log.Printf("Legacy bridge: ERROR enqueuing key %s: %v", legacyKey, err)
+			continue
 		}
 
-		// Block here until all goroutines in the current batch have called wg.Done().
-		wg.Wait()
+		rdb.Del(ctx, legacyKey)
 	}
 }
 
-// threadWorker processes a single job from Redis.
-func threadWorker(id int, wg *sync.WaitGroup, jsonString string, rdb *redis.Client, ctx context.Context, db *gorm.DB, redisKey string) {
-	// FIX: Defer wg.Done() at the top. This is the most critical fix.
-	// It guarantees that the WaitGroup is notified that this goroutine has finished,
-	// regardless of where the function returns. This prevents the program from hanging.
-	defer wg.Done()
-
-	log.Printf("Worker %d: Started processing key: %s", id, redisKey)
-
-	// Struct definitions
-	type TransactionData struct {
-		Code         string `json:"code"`
-		Desc         string `json:"desc"`
-		Msisdn       string `json:"msisdn"`
-		Operator     string `json:"operator"`
-		Shortcode    string `json:"short-code"`
-		TranRef      string `json:"tran-ref"`
-		Timestamp    int    `json:"timestamp"`
-		ReturnStatus string `json:"cyberus-return"`
-	}
-
-	type tmvh_transaction_logs struct {
-		ID            string `gorm:"primaryKey"`
-		Code          string `gorm:"column:code"`
-		Description   string `gorm:"column:description"`
-		Msisdn        string `gorm:"column:msisdn"`
-		Operator      string `gorm:"column:operator"`
-		ShortCode     string `gorm:"column:short_code"`
-		TranRef       string `gorm:"column:tran_ref"`
-		Timestamp     int64  `gorm:"column:timestamp"`
-		CyberusReturn string `gorm:"column:cyberus_return"`
-	}
-
-	type client_services struct {
-		ID              uint   `gorm:"column:id;primaryKey"`
-		DNURL           string `gorm:"column:dn_url"`
-		PostbackURL     string `gorm:"column:postback_url"`
-		PostbackCounter int    `gorm:"column:postback_counter"`
-	}
+// errMalformedPayload marks a job as permanently unprocessable: retrying it
+// would just burn attempts, so the caller should dead-letter it immediately.
+var errMalformedPayload = errors.New("malformed job payload")
+
+// threadWorker processes a single job payload popped from the reliable
+// queue. It returns nil on success, an error wrapping errMalformedPayload if
+// the payload could never be processed, or any other error for a failure the caller should
+// retry with backoff. The optional HTTP partner ping is dispatched in its
+// own detached goroutine (see sendDNPingWithRetry) so its retry/backoff loop
+// never blocks threadWorker from reaching the critical DB insert below: a
+// slow partner endpoint must not hold a job's processing-list entry idle
+// long enough for recoverStaleJobs to mistake it for a crashed worker and
+// hand the same job to a second one.
+func threadWorker(id int, jsonString string, rdb redis.UniversalClient, ctx context.Context, db *gorm.DB, inserter *LogInserter) error {
+	log.Printf("Worker %d: Started processing job", id)
+
+	inflightWorkers.Inc()
+	defer inflightWorkers.Dec()
+	start := time.Now()
+	defer func() { jobDurationSeconds.Observe(time.Since(start).Seconds()) }()
 
 	// Unmarshal the primary transaction data. If this fails, we cannot proceed.
 	var transactionData TransactionData
 	if err := json.Unmarshal([]byte(jsonString), &transactionData); err != nil {
-		log.Printf("Worker %d: ERROR - JSON Unmarshal failed for key %s: %v", id, redisKey, err)
-		// We will delete the invalid key from Redis to prevent it from being processed again.
-		rdb.Del(ctx, redisKey)
-		return // Exit this goroutine.
+		log.Printf("Worker %d: ERROR - JSON Unmarshal failed: %v", id, err)
+		return fmt.Errorf("%w: %v", errMalformedPayload, err)
 	}
 
 	// --- Optional HTTP Call ---
@@ -170,12 +419,12 @@ func threadWorker(id int, wg *sync.WaitGroup, jsonString string, rdb *redis.Clie
 		telco_operator = "3"
 	}
 
-	queryRes := db.Where("shortcode = ? and telcoid = ?", transactionData.Shortcode, telco_operator).First(&partnerDataEntry)
+	queryRes := db.WithContext(ctx).Where("shortcode = ? and telcoid = ?", transactionData.Shortcode, telco_operator).First(&partnerDataEntry)
 	if queryRes.Error != nil {
 		if queryRes.Error == gorm.ErrRecordNotFound {
-			log.Printf("Worker %d: INFO - Partner data not found for key %s. Skipping HTTP call.", id, redisKey)
+			log.Printf("Worker %d: INFO - Partner data not found. Skipping HTTP call.", id)
 		} else {
-			log.Printf("Worker %d: WARNING - DB query failed for key %s: %v. Skipping HTTP call.", id, redisKey, queryRes.Error)
+			log.Printf("Worker %d: WARNING - DB query failed: %v. Skipping HTTP call.", id, queryRes.Error)
 		}
 	} else if partnerDataEntry.DNURL != "" {
 		// This block only runs if the DB query was successful and a DNURL exists.
@@ -189,20 +438,12 @@ func threadWorker(id int, wg *sync.WaitGroup, jsonString string, rdb *redis.Clie
 		queryParams.Add("timestamp", strconv.FormatInt(int64(transactionData.Timestamp), 10))
 
 		paramTargetURL := fmt.Sprintf("%s?%s", partnerDataEntry.DNURL, queryParams.Encode())
-		client := http.Client{Timeout: 10 * time.Second}
-
-		resp, err := client.Get(paramTargetURL)
-		// FIX: Log HTTP errors but do not stop the worker's main job (DB insert).
-		if err != nil {
-			log.Printf("Worker %d: WARNING - HTTP GET request failed for key %s: %v", id, redisKey, err)
-		} else {
-			defer resp.Body.Close()
-			if resp.StatusCode != http.StatusOK {
-				log.Printf("Worker %d: WARNING - Received non-OK HTTP status for key %s: %s", id, redisKey, resp.Status)
-			} else {
-				log.Printf("Worker %d: INFO - Successfully sent DN ping for key %s", id, redisKey)
-			}
-		}
+		// Detached: threadWorker must not block on this. ctx is workCtx, which
+		// outlives the job itself and is not cancelled by the shutdown signal,
+		// so the retry loop keeps running while main() drains in-flight jobs.
+		// The drain does not wait for it: once workCtx is cancelled, a retry
+		// still pending is abandoned and not dead-lettered.
+		go sendDNPingWithRetry(ctx, id, paramTargetURL, rdb)
 	}
 
 	// --- Critical Database Insert ---
@@ -218,11 +459,14 @@ func threadWorker(id int, wg *sync.WaitGroup, jsonString string, rdb *redis.Clie
 		CyberusReturn: transactionData.ReturnStatus,
 	}
 
-	if err := db.Create(&logEntry).Error; err != nil {
-		// FIX: If the main DB insert fails, log it and exit without deleting the Redis key.
-		// This allows the job to be picked up and retried on the next scan.
-		log.Printf("Worker %d: ERROR - Database insert failed for key %s: %v. Task will be retried.", id, redisKey, err)
-		return
+	dbStart := time.Now()
+	insertErr := inserter.Insert(ctx, logEntry)
+	dbInsertDurationSeconds.Observe(time.Since(dbStart).Seconds())
+	if insertErr != nil {
+		// The caller will re-queue this with exponential backoff up to maxAttempts.
+		dbInsertErrorsTotal.Inc()
+		log.Printf("Worker %d: ERROR - Database insert failed: %v. Task will be retried.", id, insertErr)
+		return fmt.Errorf("db insert: %w", insertErr)
 	}
 
 	// --- Final Redis Operations ---
@@ -230,13 +474,69 @@ func threadWorker(id int, wg *sync.WaitGroup, jsonString string, rdb *redis.Clie
 	ttl := 240 * time.Hour // expires in 10 days
 	if err := rdb.Set(ctx, redis_set_key, jsonString, ttl).Err(); err != nil {
 		// FIX: Log this error but don't stop. The critical DB work is done.
-		log.Printf("Worker %d: WARNING - Redis SET confirmation key failed for key %s: %v", id, redisKey, err)
+		log.Printf("Worker %d: WARNING - Redis SET confirmation key failed: %v", id, err)
 	}
 
-	// Clean up the original Redis key since the job was processed successfully.
-	if err := rdb.Del(ctx, redisKey).Err(); err != nil {
-		log.Printf("Worker %d: WARNING - Failed to delete original key %s from Redis: %v", id, redisKey, err)
+	log.Printf("Worker %d: Finished processing job successfully.", id)
+	return nil
+}
+
+// sendDNPing performs a single partner DN GET request and reports whether it
+// counts as delivered (a non-OK status is treated as a failed attempt, same
+// as a transport error).
+func sendDNPing(ctx context.Context, id int, targetURL string) error {
+	client := http.Client{Timeout: 10 * time.Second}
+
+	dnStart := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	var resp *http.Response
+	if err == nil {
+		resp, err = client.Do(req)
+	}
+	dnHTTPDurationSeconds.Observe(time.Since(dnStart).Seconds())
+	if err != nil {
+		dnHTTPRequestsTotal.WithLabelValues("error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+
+	dnHTTPRequestsTotal.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-OK status: %s", resp.Status)
+	}
+
+	log.Printf("Worker %d: INFO - Successfully sent DN ping", id)
+	return nil
+}
+
+// sendDNPingWithRetry retries a failing partner DN ping with the same
+// exponential-backoff/maxAttempts policy as the job queue (see nextBackoff),
+// independently of the transaction log DB insert: the ping has its own
+// attempt count and its own dead-letter queue (dnDLQKey), and it never fails
+// or re-queues the job itself. Callers processing a job run this in its own
+// goroutine (see threadWorker) rather than waiting on it inline, since the
+// backoff schedule alone can run well past staleProcessingAfter.
+func sendDNPingWithRetry(ctx context.Context, id int, targetURL string, rdb redis.UniversalClient) {
+	dnPingsInFlight.Inc()
+	defer dnPingsInFlight.Dec()
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(nextBackoff(attempt)):
+			case <-ctx.Done():
+				log.Printf("Worker %d: WARNING - DN ping retry abandoned: %v", id, ctx.Err())
+				return
+			}
+		}
+
+		if lastErr = sendDNPing(ctx, id, targetURL); lastErr == nil {
+			return
+		}
+		log.Printf("Worker %d: WARNING - DN ping attempt %d/%d failed: %v", id, attempt+1, maxAttempts, lastErr)
 	}
 
-	log.Printf("Worker %d: Finished processing key %s successfully.", id, redisKey)
+	log.Printf("Worker %d: ERROR - DN ping exhausted %d attempts, dead-lettering: %v", id, maxAttempts, lastErr)
+	deadLetterDN(ctx, rdb, targetURL, lastErr)
 }
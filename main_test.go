@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNextBackoff covers the exponential growth and the capBackoff ceiling;
+// jitter is randomized, so each case only asserts the delay falls within the
+// [delay, 1.5*delay] band nextBackoff is documented to produce.
+func TestNextBackoff(t *testing.T) {
+	cases := []struct {
+		name     string
+		attempts int
+		wantBase time.Duration
+	}{
+		{name: "first attempt", attempts: 0, wantBase: baseBackoff},
+		{name: "second attempt", attempts: 1, wantBase: baseBackoff * 2},
+		{name: "third attempt", attempts: 2, wantBase: baseBackoff * 4},
+		{name: "capped", attempts: 10, wantBase: capBackoff},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := nextBackoff(tc.attempts)
+			if got < tc.wantBase || got > tc.wantBase+tc.wantBase/2 {
+				t.Errorf("nextBackoff(%d) = %s, want in [%s, %s]", tc.attempts, got, tc.wantBase, tc.wantBase+tc.wantBase/2)
+			}
+		})
+	}
+}
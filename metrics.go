@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const poolStatsInterval = 15 * time.Second
+
+var (
+	jobsProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_processed_total",
+		Help: "Total number of jobs consumeQueue has finished handling, by outcome.",
+	}, []string{"result"})
+
+	dnHTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dn_http_requests_total",
+		Help: "Total number of DN partner ping requests, by outcome status.",
+	}, []string{"status"})
+
+	dbInsertErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "db_insert_errors_total",
+		Help: "Total number of transaction log insert failures.",
+	})
+
+	jobDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "job_duration_seconds",
+		Help:    "Time spent processing a single job end-to-end in threadWorker.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	dnHTTPDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "dn_http_duration_seconds",
+		Help:    "Time spent waiting on the DN partner ping HTTP request.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	dbInsertDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "db_insert_duration_seconds",
+		Help:    "Time a job spent waiting on its transaction log row to be inserted.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	redisScanBatchSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_scan_batch_size",
+		Help: "Current depth of the main job queue, sampled periodically (named for the SCAN-era metric it replaces).",
+	})
+
+	inflightWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "inflight_workers",
+		Help: "Number of threadWorker goroutines currently processing a job.",
+	})
+
+	dnPingsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dn_pings_in_flight",
+		Help: "Number of detached sendDNPingWithRetry goroutines currently retrying a partner DN ping.",
+	})
+
+	redisPoolHits = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_hits",
+		Help: "Cumulative number of times a free Redis connection was found in the pool.",
+	})
+
+	redisPoolMisses = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_pool_misses",
+		Help: "Cumulative number of times a free Redis connection was NOT found in the pool.",
+	})
+
+	dbOpenConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "db_open_connections",
+		Help: "Number of established Postgres connections (in use + idle).",
+	})
+
+	dbInUseConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "db_in_use_connections",
+		Help: "Number of Postgres connections currently in use.",
+	})
+
+	dbIdleConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "db_idle_connections",
+		Help: "Number of idle Postgres connections.",
+	})
+)
+
+// startMetricsServer exposes /metrics and the standard /debug/pprof/*
+// profiling endpoints on addr. It's expected to run for the lifetime of the
+// process, so the caller should invoke it in its own goroutine.
+func startMetricsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	log.Printf("Metrics/pprof server listening on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Metrics/pprof server stopped: %v", err)
+	}
+}
+
+// reportPoolStats periodically samples the Redis connection pool, the GORM
+// connection pool, and the main queue depth into the gauges above so they
+// show up in Grafana/alerting without the worker needing to be instrumented
+// inline on every call.
+func reportPoolStats(rdb redis.UniversalClient, db *gorm.DB) {
+	ctx := context.Background()
+	ticker := time.NewTicker(poolStatsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		poolStats := rdb.PoolStats()
+		redisPoolHits.Set(float64(poolStats.Hits))
+		redisPoolMisses.Set(float64(poolStats.Misses))
+
+		if queueLen, err := rdb.LLen(ctx, queueKey).Result(); err == nil {
+			redisScanBatchSize.Set(float64(queueLen))
+		}
+
+		if sqlDB, err := db.DB(); err == nil {
+			dbStats := sqlDB.Stats()
+			dbOpenConnections.Set(float64(dbStats.OpenConnections))
+			dbInUseConnections.Set(float64(dbStats.InUse))
+			dbIdleConnections.Set(float64(dbStats.Idle))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// getEnvInt reads an integer env var, falling back to def if it is unset or unparsable.
+func getEnvInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("WARNING: %s=%q is not a valid integer, using default %d", key, val, def)
+		return def
+	}
+	return n
+}
+
+// getEnvDuration reads a duration in seconds from an env var, falling back to def.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	secs, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("WARNING: %s=%q is not a valid integer, using default %s", key, val, def)
+		return def
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// poolOptions reads the pool/timeout tuning knobs shared by every connection
+// mode (standalone, Sentinel, Cluster) from their BN_REDIS_* env vars.
+type poolOptions struct {
+	poolSize     int
+	minIdleConns int
+	dialTimeout  time.Duration
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	db           int
+	// dbSet reports whether BN_REDIS_DB was actually present in the
+	// environment, so callers that can otherwise infer the DB index (e.g.
+	// from the BN_REDIS_URL path) know whether to let it win over db's
+	// zero-value default instead of always overriding with it.
+	dbSet bool
+}
+
+func loadPoolOptions() poolOptions {
+	_, dbSet := os.LookupEnv("BN_REDIS_DB")
+	return poolOptions{
+		poolSize:     getEnvInt("BN_REDIS_POOL_SIZE", 100),
+		minIdleConns: getEnvInt("BN_REDIS_MIN_IDLE_CONNS", 0),
+		dialTimeout:  getEnvDuration("BN_REDIS_DIAL_TIMEOUT", 5*time.Second),
+		readTimeout:  getEnvDuration("BN_REDIS_READ_TIMEOUT", 3*time.Second),
+		writeTimeout: getEnvDuration("BN_REDIS_WRITE_TIMEOUT", 3*time.Second),
+		db:           getEnvInt("BN_REDIS_DB", 0),
+		dbSet:        dbSet,
+	}
+}
+
+// newRedisClient builds a Redis client from BN_REDIS_URL, supporting plain
+// standalone instances (redis://, rediss:// for TLS), Sentinel-backed HA
+// topologies (redis+sentinel://) and Redis Cluster (redis+cluster://), so the
+// worker can be deployed against any of them without a rebuild.
+func newRedisClient(rawURL string) redis.UniversalClient {
+	pool := loadPoolOptions()
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		if strings.Contains(rawURL, "://") {
+			log.Fatalf("Failed to parse BN_REDIS_URL: %v", err)
+		}
+		// A bare "host:port" with an IP-literal host doesn't parse as a URL;
+		// leave the scheme empty so it falls through to the legacy Addr case.
+		parsed = &url.URL{}
+	}
+
+	switch parsed.Scheme {
+	case "redis+cluster":
+		password := ""
+		if parsed.User != nil {
+			password, _ = parsed.User.Password()
+		}
+
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        strings.Split(parsed.Host, ","),
+			Password:     password,
+			PoolSize:     pool.poolSize,
+			MinIdleConns: pool.minIdleConns,
+			DialTimeout:  pool.dialTimeout,
+			ReadTimeout:  pool.readTimeout,
+			WriteTimeout: pool.writeTimeout,
+		})
+
+	case "redis+sentinel":
+		masterName := os.Getenv("BN_REDIS_SENTINEL_MASTER")
+		if masterName == "" {
+			log.Fatal("BN_REDIS_SENTINEL_MASTER must be set when BN_REDIS_URL uses the redis+sentinel:// scheme.")
+		}
+
+		sentinelAddrs := strings.Split(parsed.Host, ",")
+
+		password := ""
+		if parsed.User != nil {
+			password, _ = parsed.User.Password()
+		}
+
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       masterName,
+			SentinelAddrs:    sentinelAddrs,
+			SentinelPassword: os.Getenv("BN_REDIS_SENTINEL_PASSWORD"),
+			Password:         password,
+			DB:               pool.db,
+			PoolSize:         pool.poolSize,
+			MinIdleConns:     pool.minIdleConns,
+			DialTimeout:      pool.dialTimeout,
+			ReadTimeout:      pool.readTimeout,
+			WriteTimeout:     pool.writeTimeout,
+		})
+
+	case "redis", "rediss":
+		opts, err := redis.ParseURL(rawURL)
+		if err != nil {
+			log.Fatalf("Failed to parse BN_REDIS_URL: %v", err)
+		}
+		// redis.ParseURL already resolved the DB index from the URL path (e.g.
+		// redis://host:6379/3); only let BN_REDIS_DB override that when it was
+		// actually set, so migrating an existing URL that encodes a non-zero DB
+		// doesn't silently fall back to DB 0.
+		if pool.dbSet {
+			opts.DB = pool.db
+		}
+		opts.PoolSize = pool.poolSize
+		opts.MinIdleConns = pool.minIdleConns
+		opts.DialTimeout = pool.dialTimeout
+		opts.ReadTimeout = pool.readTimeout
+		opts.WriteTimeout = pool.writeTimeout
+		return redis.NewClient(opts)
+
+	default:
+		// Legacy deployments set BN_REDIS_URL to a bare "host:port" Addr.
+		return redis.NewClient(&redis.Options{
+			Addr:         rawURL,
+			DB:           pool.db,
+			PoolSize:     pool.poolSize,
+			MinIdleConns: pool.minIdleConns,
+			DialTimeout:  pool.dialTimeout,
+			ReadTimeout:  pool.readTimeout,
+			WriteTimeout: pool.writeTimeout,
+		})
+	}
+}
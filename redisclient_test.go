@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestNewRedisClient_SchemeDispatch covers the BN_REDIS_URL scheme dispatch
+// in newRedisClient: standalone/TLS and bare host:port URLs should produce a
+// plain *redis.Client, redis+sentinel:// a failover-backed *redis.Client, and
+// redis+cluster:// a *redis.ClusterClient.
+func TestNewRedisClient_SchemeDispatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		rawURL  string
+		setup   func(t *testing.T)
+		checkFn func(t *testing.T, rdb redis.UniversalClient)
+	}{
+		{
+			name:   "standalone",
+			rawURL: "redis://localhost:6379",
+			checkFn: func(t *testing.T, rdb redis.UniversalClient) {
+				if _, ok := rdb.(*redis.Client); !ok {
+					t.Fatalf("expected *redis.Client, got %T", rdb)
+				}
+			},
+		},
+		{
+			name:   "tls",
+			rawURL: "rediss://localhost:6379",
+			checkFn: func(t *testing.T, rdb redis.UniversalClient) {
+				if _, ok := rdb.(*redis.Client); !ok {
+					t.Fatalf("expected *redis.Client, got %T", rdb)
+				}
+			},
+		},
+		{
+			name:   "legacy bare addr",
+			rawURL: "localhost:6379",
+			checkFn: func(t *testing.T, rdb redis.UniversalClient) {
+				if _, ok := rdb.(*redis.Client); !ok {
+					t.Fatalf("expected *redis.Client, got %T", rdb)
+				}
+			},
+		},
+		{
+			name:   "sentinel",
+			rawURL: "redis+sentinel://sentinel1:26379,sentinel2:26379",
+			setup: func(t *testing.T) {
+				t.Setenv("BN_REDIS_SENTINEL_MASTER", "mymaster")
+			},
+			checkFn: func(t *testing.T, rdb redis.UniversalClient) {
+				if _, ok := rdb.(*redis.Client); !ok {
+					t.Fatalf("expected *redis.Client, got %T", rdb)
+				}
+			},
+		},
+		{
+			name:   "cluster",
+			rawURL: "redis+cluster://node1:7000,node2:7000",
+			checkFn: func(t *testing.T, rdb redis.UniversalClient) {
+				if _, ok := rdb.(*redis.ClusterClient); !ok {
+					t.Fatalf("expected *redis.ClusterClient, got %T", rdb)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				tc.setup(t)
+			}
+			rdb := newRedisClient(tc.rawURL)
+			defer rdb.Close()
+			tc.checkFn(t, rdb)
+		})
+	}
+}
+
+// TestNewRedisClient_PreservesURLDBIndex covers the redis://, rediss:// DB
+// selection: the DB index encoded in BN_REDIS_URL's path must survive unless
+// BN_REDIS_DB is explicitly set, so migrating an existing URL that points at
+// a non-zero DB doesn't silently start reading/writing DB 0.
+func TestNewRedisClient_PreservesURLDBIndex(t *testing.T) {
+	t.Run("BN_REDIS_DB unset keeps the URL's DB index", func(t *testing.T) {
+		rdb := newRedisClient("redis://localhost:6379/3")
+		defer rdb.Close()
+
+		client, ok := rdb.(*redis.Client)
+		if !ok {
+			t.Fatalf("expected *redis.Client, got %T", rdb)
+		}
+		if got := client.Options().DB; got != 3 {
+			t.Errorf("expected DB 3 from the URL, got %d", got)
+		}
+	})
+
+	t.Run("BN_REDIS_DB set overrides the URL's DB index", func(t *testing.T) {
+		t.Setenv("BN_REDIS_DB", "5")
+		rdb := newRedisClient("redis://localhost:6379/3")
+		defer rdb.Close()
+
+		client, ok := rdb.(*redis.Client)
+		if !ok {
+			t.Fatalf("expected *redis.Client, got %T", rdb)
+		}
+		if got := client.Options().DB; got != 5 {
+			t.Errorf("expected DB 5 from BN_REDIS_DB, got %d", got)
+		}
+	})
+}
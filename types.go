@@ -0,0 +1,36 @@
+package main
+
+// TransactionData is the shape of the job payload producers enqueue.
+type TransactionData struct {
+	Code         string `json:"code"`
+	Desc         string `json:"desc"`
+	Msisdn       string `json:"msisdn"`
+	Operator     string `json:"operator"`
+	Shortcode    string `json:"short-code"`
+	TranRef      string `json:"tran-ref"`
+	Timestamp    int    `json:"timestamp"`
+	ReturnStatus string `json:"cyberus-return"`
+}
+
+// tmvh_transaction_logs mirrors the tmvh_transaction_logs table that every
+// processed job is recorded into.
+type tmvh_transaction_logs struct {
+	ID            string `gorm:"primaryKey"`
+	Code          string `gorm:"column:code"`
+	Description   string `gorm:"column:description"`
+	Msisdn        string `gorm:"column:msisdn"`
+	Operator      string `gorm:"column:operator"`
+	ShortCode     string `gorm:"column:short_code"`
+	TranRef       string `gorm:"column:tran_ref"`
+	Timestamp     int64  `gorm:"column:timestamp"`
+	CyberusReturn string `gorm:"column:cyberus_return"`
+}
+
+// client_services mirrors the per-partner DN/postback configuration looked
+// up for each transaction's operator + shortcode.
+type client_services struct {
+	ID              uint   `gorm:"column:id;primaryKey"`
+	DNURL           string `gorm:"column:dn_url"`
+	PostbackURL     string `gorm:"column:postback_url"`
+	PostbackCounter int    `gorm:"column:postback_counter"`
+}